@@ -0,0 +1,110 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"context"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const chartYAMLPolicy = "chart-yaml"
+
+// chartMetadata mirrors the fields Helm's Chart.yaml schema requires,
+// across both the v2 (apiVersion: v1) and v3 (apiVersion: v2) chart
+// formats. See https://helm.sh/docs/topics/charts/#the-chartyaml-file.
+type chartMetadata struct {
+	APIVersion string `yaml:"apiVersion"`
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+}
+
+// chartYAMLValidationPolicy validates Chart.yaml against the Helm v2/v3
+// chart schemas, so that malformed charts are rejected with a clear
+// violation instead of failing further down the pipeline with a confusing
+// error.
+type chartYAMLValidationPolicy struct{}
+
+func (p *chartYAMLValidationPolicy) Name() string { return chartYAMLPolicy }
+
+func (p *chartYAMLValidationPolicy) Severity() Severity { return SeverityMedium }
+
+func (p *chartYAMLValidationPolicy) InspectChart(_ context.Context, chart Chart) []Violation {
+	file, ok := findFile(chart, "Chart.yaml")
+	if !ok {
+		return []Violation{{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			Message:  "chart archive is missing Chart.yaml",
+		}}
+	}
+
+	var meta chartMetadata
+	if err := yaml.Unmarshal(file.Content, &meta); err != nil {
+		return []Violation{{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     file.Name(),
+			Message:  "Chart.yaml is not valid YAML: " + err.Error(),
+		}}
+	}
+
+	var violations []Violation
+
+	switch meta.APIVersion {
+	case "v1", "v2":
+	default:
+		violations = append(violations, Violation{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     file.Name(),
+			Message:  "Chart.yaml apiVersion must be \"v1\" (Helm 2) or \"v2\" (Helm 3)",
+		})
+	}
+
+	if meta.Name == "" {
+		violations = append(violations, Violation{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     file.Name(),
+			Message:  "Chart.yaml is missing the required \"name\" field",
+		})
+	}
+
+	if meta.Version == "" {
+		violations = append(violations, Violation{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     file.Name(),
+			Message:  "Chart.yaml is missing the required \"version\" field",
+		})
+	}
+
+	return violations
+}
+
+// findFile returns the chart file whose name ends with path, tolerating the
+// chart's top-level directory prefix (e.g. "mychart/Chart.yaml").
+func findFile(chart Chart, path string) (ChartFile, bool) {
+	for _, f := range chart.Files {
+		name := f.Name()
+		if name == path || strings.HasSuffix(name, "/"+path) {
+			return f, true
+		}
+	}
+
+	return ChartFile{}, false
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_ChartYAML(t *testing.T) {
+	tests := map[string]struct {
+		chartYAML      string
+		wantViolations int
+	}{
+		"valid v2 chart": {
+			chartYAML:      "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+			wantViolations: 0,
+		},
+		"valid v1 chart": {
+			chartYAML:      "apiVersion: v1\nname: mychart\nversion: 0.1.0\n",
+			wantViolations: 0,
+		},
+		"missing name and version": {
+			chartYAML:      "apiVersion: v2\n",
+			wantViolations: 2,
+		},
+		"unsupported apiVersion": {
+			chartYAML:      "apiVersion: v3\nname: mychart\nversion: 0.1.0\n",
+			wantViolations: 1,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			archive := buildChartArchive(t, []tarEntry{
+				{name: "mychart/Chart.yaml", content: test.chartYAML},
+			})
+
+			report, err := NewChartScanner().Report(bytes.NewReader(archive))
+			require.NoError(t, err)
+
+			var chartYAMLViolations int
+			for _, f := range report.Findings {
+				if f.Policy == chartYAMLPolicy {
+					chartYAMLViolations++
+				}
+			}
+			assert.Equal(t, test.wantViolations, chartYAMLViolations)
+		})
+	}
+}
+
+func TestReport_MissingChartYAML(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/templates/deployment.yaml", content: "kind: Deployment\n"},
+	})
+
+	report, err := NewChartScanner().Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, chartYAMLPolicy, report.Findings[0].Policy)
+}
+
+func TestReport_ChartNameAndVersion(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 1.2.3\n"},
+	})
+
+	report, err := NewChartScanner().Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	assert.Equal(t, "mychart", report.ChartName)
+	assert.Equal(t, "1.2.3", report.ChartVersion)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Fetcher retrieves the gzip-compressed tar bytes of a chart referenced by
+// a scheme-specific ref (an OCI artifact reference, a chart repository
+// URL, etc.), streaming them rather than writing them to disk.
+type Fetcher interface {
+	// Schemes are the URI schemes this Fetcher handles, e.g. {"oci"} or
+	// {"http", "https"}.
+	Schemes() []string
+
+	// Fetch resolves ref and returns a reader over the chart's .tgz bytes.
+	// The caller is responsible for closing the returned ReadCloser.
+	Fetch(ctx context.Context, ref string) (io.ReadCloser, error)
+}
+
+type fetchConfig struct {
+	fetchers map[string]Fetcher
+}
+
+func newFetchConfig() *fetchConfig {
+	cfg := &fetchConfig{fetchers: make(map[string]Fetcher)}
+
+	for _, f := range defaultFetchers() {
+		registerFetcher(cfg, f)
+	}
+
+	return cfg
+}
+
+func defaultFetchers() []Fetcher {
+	return []Fetcher{
+		&httpFetcher{client: http.DefaultClient},
+		&ociFetcher{},
+	}
+}
+
+func registerFetcher(cfg *fetchConfig, f Fetcher) {
+	for _, scheme := range f.Schemes() {
+		cfg.fetchers[scheme] = f
+	}
+}
+
+// FetchOption configures how ScanRef fetches a chart reference.
+type FetchOption func(*fetchConfig)
+
+// WithFetcher registers a Fetcher, adding it to the set ScanRef consults
+// for each of its schemes, replacing any built-in fetcher registered for
+// the same scheme. This lets callers plug in, for instance, an
+// authenticated transport without forking the scanner.
+func WithFetcher(f Fetcher) FetchOption {
+	return func(cfg *fetchConfig) {
+		registerFetcher(cfg, f)
+	}
+}
+
+// ScanRef fetches the chart at ref - an "oci://" artifact reference or an
+// "https://" chart repository URL - and scans it, without ever persisting
+// the fetched bytes to disk: the Fetcher's reader is streamed directly
+// into ReportContext, the same as it would be for a local archive.
+func (s *ChartScanner) ScanRef(ctx context.Context, ref string, opts ...FetchOption) (*ScanReport, error) {
+	cfg := newFetchConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid chart reference %q", ref)
+	}
+
+	fetcher, ok := cfg.fetchers[u.Scheme]
+	if !ok {
+		return nil, errors.Errorf("no fetcher registered for scheme %q", u.Scheme)
+	}
+
+	rc, err := fetcher.Fetch(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch chart %q", ref)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	return s.ReportContext(ctx, rc)
+}
@@ -0,0 +1,210 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"gopkg.in/yaml.v3"
+)
+
+// maxFetchResponseSize bounds how many bytes get will read from a single
+// HTTP response (a chart .tgz, an index.yaml, or a .prov file), mirroring
+// ChartScanner's countingReader guard against materializing an arbitrarily
+// large response in memory before it ever reaches that guard.
+const maxFetchResponseSize = 10 * 1024 * 1024 // 10 MB
+
+// httpFetcher fetches charts over HTTP(S), the transport Helm chart
+// repositories use. ref is either a direct URL to a chart's .tgz, as
+// published in a repository's index.yaml "urls" field, or a repository
+// index URL with a "#chart:version" fragment (e.g.
+// "https://charts.example.com/index.yaml#mychart:1.2.3"), which is
+// resolved against the index before fetching.
+type httpFetcher struct {
+	client *http.Client
+
+	// keyring, if set, enables verification of a fetched chart's .prov
+	// file against it; an unsigned or incorrectly signed chart is
+	// rejected. It is nil by default: provenance verification is opt-in,
+	// since not every chart repository publishes .prov files.
+	keyring openpgp.EntityList
+}
+
+func (f *httpFetcher) Schemes() []string { return []string{"http", "https"} }
+
+func (f *httpFetcher) Fetch(ctx context.Context, ref string) (io.ReadCloser, error) {
+	chartURL, err := f.resolveChartURL(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.get(ctx, chartURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.keyring != nil {
+		if err := f.verifyProvenance(ctx, chartURL, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// resolveChartURL returns the direct download URL for ref. If ref does not
+// reference a repository index, it is assumed to already be a direct
+// chart URL and is returned unchanged.
+func (f *httpFetcher) resolveChartURL(ctx context.Context, ref string) (string, error) {
+	indexURL, fragment, hasFragment := strings.Cut(ref, "#")
+	if !hasFragment || !strings.HasSuffix(indexURL, "index.yaml") {
+		return ref, nil
+	}
+
+	chartName, version, ok := strings.Cut(fragment, ":")
+	if !ok {
+		return "", errors.Errorf("chart repository reference %q must have a #chart:version fragment", ref)
+	}
+
+	data, err := f.get(ctx, indexURL)
+	if err != nil {
+		return "", err
+	}
+
+	var index chartRepoIndex
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return "", errors.Wrapf(err, "failed to parse chart repository index %q", indexURL)
+	}
+
+	versions, ok := index.Entries[chartName]
+	if !ok {
+		return "", errors.Errorf("chart %q not found in repository index %q", chartName, indexURL)
+	}
+
+	for _, v := range versions {
+		if v.Version != version {
+			continue
+		}
+
+		if len(v.URLs) == 0 {
+			return "", errors.Errorf("chart %s:%s has no download URLs in repository index %q", chartName, version, indexURL)
+		}
+
+		return resolveIndexURL(indexURL, v.URLs[0])
+	}
+
+	return "", errors.Errorf("version %q of chart %q not found in repository index %q", version, chartName, indexURL)
+}
+
+// chartRepoIndex is the subset of a Helm chart repository's index.yaml
+// this fetcher needs to resolve a chart:version reference to a URL.
+type chartRepoIndex struct {
+	Entries map[string][]struct {
+		Version string   `yaml:"version"`
+		URLs    []string `yaml:"urls"`
+	} `yaml:"entries"`
+}
+
+// resolveIndexURL resolves a chart URL found in a repository index, which
+// Helm allows to be either absolute or relative to the index itself.
+func resolveIndexURL(indexURL, chartURL string) (string, error) {
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid repository index URL %q", indexURL)
+	}
+
+	ref, err := url.Parse(chartURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid chart URL %q", chartURL)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (f *httpFetcher) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid URL %q", rawURL)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %q", rawURL)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetching %q returned HTTP %d", rawURL, resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFetchResponseSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response body from %q", rawURL)
+	}
+
+	if int64(len(data)) > maxFetchResponseSize {
+		return nil, errors.Errorf("response from %q exceeds the %d byte fetch size limit", rawURL, maxFetchResponseSize)
+	}
+
+	return data, nil
+}
+
+// verifyProvenance fetches chartURL's .prov file and checks that it is a
+// validly PGP-signed attestation of chartData's SHA-256 digest, mirroring
+// Helm's own provenance verification (`helm verify`). It has to buffer the
+// whole chart in memory first, since the signature covers the complete
+// archive; this package never buffers to disk to do so.
+func (f *httpFetcher) verifyProvenance(ctx context.Context, chartURL string, chartData []byte) error {
+	provData, err := f.get(ctx, chartURL+".prov")
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch chart provenance file")
+	}
+
+	block, _ := clearsign.Decode(provData)
+	if block == nil {
+		return errors.Errorf("provenance file for %q is not a valid PGP clearsigned message", chartURL)
+	}
+
+	if _, err := openpgp.CheckDetachedSignature(f.keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return errors.Wrapf(err, "provenance signature verification failed for %q", chartURL)
+	}
+
+	sum := sha256.Sum256(chartData)
+	if !strings.Contains(string(block.Plaintext), hex.EncodeToString(sum[:])) {
+		return errors.Errorf("chart %q does not match the digest recorded in its provenance file", chartURL)
+	}
+
+	return nil
+}
+
+// WithProvenanceVerification enables verification of a fetched chart's
+// .prov file against keyring, rejecting the chart if it is unsigned or the
+// signature or digest does not check out.
+func WithProvenanceVerification(keyring openpgp.EntityList) FetchOption {
+	return func(cfg *fetchConfig) {
+		registerFetcher(cfg, &httpFetcher{client: http.DefaultClient, keyring: keyring})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanRef_DirectHTTPURL(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	report, err := NewChartScanner().ScanRef(context.Background(), server.URL+"/mychart-0.1.0.tgz")
+	require.NoError(t, err)
+	assert.Equal(t, "mychart", report.ChartName)
+}
+
+func TestScanRef_ResolvesRepositoryIndex(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 1.2.3\n"},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("entries:\n  mychart:\n  - version: 1.2.3\n    urls:\n    - mychart-1.2.3.tgz\n"))
+	})
+	mux.HandleFunc("/mychart-1.2.3.tgz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	report, err := NewChartScanner().ScanRef(context.Background(), server.URL+"/index.yaml#mychart:1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", report.ChartVersion)
+}
+
+func TestScanRef_UnknownScheme(t *testing.T) {
+	_, err := NewChartScanner().ScanRef(context.Background(), "ftp://example.com/mychart.tgz")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no fetcher registered")
+}
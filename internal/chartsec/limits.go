@@ -0,0 +1,114 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const defaultMaxCompressionRatio = 100
+
+const compressionRatioPolicy = "compression-ratio"
+
+// errArchiveTooLarge, errUncompressedTooLarge and errCompressionRatioExceeded
+// are sentinel errors surfaced by the guarded readers below. report() turns
+// them into Violations instead of wrapping and returning them, since they
+// are expected outcomes of a hostile archive, not extraction failures.
+var (
+	errArchiveTooLarge          = errors.New("chart is too large")
+	errUncompressedTooLarge     = errors.New("chart is too large")
+	errCompressionRatioExceeded = errors.New("chart archive exceeds its allowed compression ratio")
+)
+
+// countingReader wraps an io.Reader, counting the bytes read through it and
+// failing once that count exceeds max. Bounding the read here, rather than
+// buffering up to max bytes before acting on them, means a hostile archive
+// is rejected as the first over-limit byte is read instead of after being
+// fully materialized in memory.
+type countingReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+	err error // sentinel returned once n exceeds max
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	if c.n > c.max {
+		// Discard the bytes from this call rather than returning them
+		// alongside the error: io.ReadAtLeast/io.ReadFull ignore a
+		// non-nil error if the read it came with already filled the
+		// destination buffer, which would silently swallow the guard.
+		return 0, c.err
+	}
+
+	return n, err
+}
+
+// ratioGuardedReader wraps a gzip.Reader, failing as soon as the ratio of
+// bytes it has produced to the compressed bytes its source has consumed
+// exceeds maxRatio, or the total bytes it has produced exceeds
+// maxUncompressed. Checking on every Read call, rather than once per
+// archive or once per tar entry, means a single oversized tar entry is
+// caught while ioutil.ReadAll is still growing its buffer to read it,
+// instead of after it has already finished (and potentially exhausted
+// memory doing so).
+type ratioGuardedReader struct {
+	gzr        io.Reader
+	compressed *countingReader
+
+	maxUncompressed int64
+	maxRatio        int64
+
+	uncompressed int64
+}
+
+func (r *ratioGuardedReader) Read(p []byte) (int, error) {
+	n, err := r.gzr.Read(p)
+	r.uncompressed += int64(n)
+
+	// See countingReader.Read for why these return 0 bytes alongside the
+	// error instead of n.
+	if r.uncompressed > r.maxUncompressed {
+		return 0, errUncompressedTooLarge
+	}
+
+	if compressed := r.compressed.n; compressed > 0 && (r.uncompressed/compressed > r.maxRatio ||
+		(r.uncompressed/compressed == r.maxRatio && r.uncompressed%compressed > 0)) {
+		return 0, errCompressionRatioExceeded
+	}
+
+	return n, err
+}
+
+// archiveLimitViolation maps a sentinel error from countingReader or
+// ratioGuardedReader to the Violation report() should report for it, or
+// returns nil if err is not one of those sentinels.
+func archiveLimitViolation(err error) *Violation {
+	switch {
+	case errors.Is(err, errArchiveTooLarge):
+		return &Violation{Policy: compressedArchiveSizePolicy, Severity: SeverityHigh, Message: err.Error()}
+	case errors.Is(err, errUncompressedTooLarge):
+		return &Violation{Policy: uncompressedArchiveSizePolicy, Severity: SeverityHigh, Message: err.Error()}
+	case errors.Is(err, errCompressionRatioExceeded):
+		return &Violation{Policy: compressionRatioPolicy, Severity: SeverityHigh, Message: err.Error()}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan_CompressionRatioExceeded(t *testing.T) {
+	// A single repeated byte compresses at a ratio far above any sane
+	// threshold, mimicking a zip-bomb tar entry.
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/NOTES.txt", content: strings.Repeat("A", 256*1024)},
+	))
+
+	err := NewChartScanner(WithMaxCompressionRatio(10)).Scan(bytes.NewReader(archive))
+	require.Error(t, err)
+
+	scanErr, ok := err.(*ScanError)
+	require.True(t, ok, "expected a *ScanError, got %T", err)
+	require.Len(t, scanErr.Violations, 1)
+	assert.Equal(t, compressionRatioPolicy, scanErr.Violations[0].Policy)
+}
+
+func TestScan_CompressionRatioWithinLimit(t *testing.T) {
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/NOTES.txt", content: strings.Repeat("A", 256*1024)},
+	))
+
+	err := NewChartScanner(WithMaxCompressionRatio(1000000)).Scan(bytes.NewReader(archive))
+	assert.NoError(t, err)
+}
+
+func TestScan_MaxUncompressedSize(t *testing.T) {
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/NOTES.txt", content: strings.Repeat("A", 256*1024)},
+	))
+
+	err := NewChartScanner(
+		WithMaxUncompressedSize(1024),
+		WithMaxCompressionRatio(1000000),
+	).Scan(bytes.NewReader(archive))
+	require.Error(t, err)
+
+	scanErr, ok := err.(*ScanError)
+	require.True(t, ok, "expected a *ScanError, got %T", err)
+	require.Len(t, scanErr.Violations, 1)
+	assert.Equal(t, uncompressedArchiveSizePolicy, scanErr.Violations[0].Policy)
+}
+
+func TestScan_MaxCompressedSize(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+	})
+
+	err := NewChartScanner(WithMaxCompressedSize(1)).Scan(bytes.NewReader(archive))
+	require.Error(t, err)
+
+	scanErr, ok := err.(*ScanError)
+	require.True(t, ok, "expected a *ScanError, got %T", err)
+	require.Len(t, scanErr.Violations, 1)
+	assert.Equal(t, compressedArchiveSizePolicy, scanErr.Violations[0].Policy)
+}
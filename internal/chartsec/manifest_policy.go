@@ -0,0 +1,233 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const k8sManifestPolicy = "k8s-manifest"
+
+// k8sManifestLintPolicy renders every chart template with its default
+// values and lints the resulting Kubernetes manifests for settings that
+// weaken the cluster's isolation: host networking, privileged containers,
+// hostPath volumes and floating ":latest" image tags.
+type k8sManifestLintPolicy struct{}
+
+func (p *k8sManifestLintPolicy) Name() string { return k8sManifestPolicy }
+
+func (p *k8sManifestLintPolicy) Severity() Severity { return SeverityHigh }
+
+func (p *k8sManifestLintPolicy) InspectChart(ctx context.Context, chart Chart) []Violation {
+	root, violations := parseChartTemplates(chart)
+
+	renderCtx := buildRenderContext(chart)
+
+	for _, tmpl := range root.Templates() {
+		if ctx.Err() != nil {
+			return violations
+		}
+
+		name := tmpl.Name()
+		if !isChartTemplate(name) || tmpl.Tree == nil {
+			continue
+		}
+
+		rendered, err := executeTemplate(root, name, renderCtx)
+		if err != nil {
+			violations = append(violations, Violation{
+				Policy:   p.Name(),
+				Severity: SeverityMedium,
+				File:     name,
+				Message:  fmt.Sprintf("template %q failed to render with default values: %s", name, err),
+			})
+
+			continue
+		}
+
+		violations = append(violations, lintManifests(name, rendered)...)
+	}
+
+	return violations
+}
+
+// buildRenderContext builds the minimal Helm-style "." render context
+// (.Values, .Chart, .Release) a template needs to render with its chart
+// defaults, mirroring `helm template` with no overrides.
+func buildRenderContext(chart Chart) map[string]interface{} {
+	values := map[string]interface{}{}
+	if file, ok := findFile(chart, "values.yaml"); ok {
+		_ = yaml.Unmarshal(file.Content, &values)
+	}
+
+	chartMeta := map[string]interface{}{}
+	if file, ok := findFile(chart, "Chart.yaml"); ok {
+		_ = yaml.Unmarshal(file.Content, &chartMeta)
+	}
+
+	return map[string]interface{}{
+		"Values": values,
+		"Chart":  chartMeta,
+		"Release": map[string]interface{}{
+			"Name":      "release-name",
+			"Namespace": "default",
+		},
+	}
+}
+
+// executeTemplate renders the named template, recovering from panics since
+// an untrusted chart's template can legitimately panic text/template (e.g.
+// by indexing a nil map) and that must surface as a violation, not crash
+// the scan.
+func executeTemplate(root *template.Template, name string, data interface{}) (out string, err error) {
+	var buf bytes.Buffer
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while rendering: %v", r)
+		}
+	}()
+
+	if execErr := root.ExecuteTemplate(&buf, name, data); execErr != nil {
+		return "", execErr
+	}
+
+	return buf.String(), nil
+}
+
+// lintManifests decodes every "---"-separated YAML document rendered from
+// file and flags unsafe Kubernetes settings found in any of them.
+func lintManifests(file, rendered string) []Violation {
+	var violations []Violation
+
+	decoder := yaml.NewDecoder(strings.NewReader(rendered))
+
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		violations = append(violations, lintManifestNode(file, doc.Content[0])...)
+	}
+
+	return violations
+}
+
+// lintManifestNode recursively walks a decoded YAML mapping/sequence,
+// flagging hostNetwork, privileged containers, hostPath volumes and
+// floating/missing image tags wherever they appear, regardless of which
+// Kubernetes kind or field nests them.
+func lintManifestNode(file string, node *yaml.Node) []Violation {
+	if node == nil {
+		return nil
+	}
+
+	var violations []Violation
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			value := node.Content[i+1]
+
+			switch key.Value {
+			case "hostNetwork":
+				if value.Value == "true" {
+					violations = append(violations, Violation{
+						Policy:   k8sManifestPolicy,
+						Severity: SeverityHigh,
+						File:     file,
+						Line:     key.Line,
+						Message:  "manifest sets hostNetwork: true, sharing the node's network namespace",
+					})
+				}
+			case "privileged":
+				if value.Value == "true" {
+					violations = append(violations, Violation{
+						Policy:   k8sManifestPolicy,
+						Severity: SeverityHigh,
+						File:     file,
+						Line:     key.Line,
+						Message:  "container runs with privileged: true",
+					})
+				}
+			case "hostPath":
+				violations = append(violations, Violation{
+					Policy:   k8sManifestPolicy,
+					Severity: SeverityMedium,
+					File:     file,
+					Line:     key.Line,
+					Message:  "volume mounts a hostPath, exposing the node's filesystem to the pod",
+				})
+			case "image":
+				if value.Kind == yaml.ScalarNode {
+					if v := imageTagViolation(value.Value); v != "" {
+						violations = append(violations, Violation{
+							Policy:   k8sManifestPolicy,
+							Severity: SeverityMedium,
+							File:     file,
+							Line:     value.Line,
+							Message:  v,
+						})
+					}
+				}
+			}
+
+			violations = append(violations, lintManifestNode(file, value)...)
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			violations = append(violations, lintManifestNode(file, child)...)
+		}
+	}
+
+	return violations
+}
+
+// imageTagViolation returns a violation message if image has no tag or is
+// pinned to the floating "latest" tag, or "" if the tag looks pinned.
+func imageTagViolation(image string) string {
+	// A digest reference (name@sha256:...) is always pinned.
+	if strings.Contains(image, "@") {
+		return ""
+	}
+
+	lastSegment := image
+	if i := strings.LastIndex(image, "/"); i != -1 {
+		lastSegment = image[i+1:]
+	}
+
+	if !strings.Contains(lastSegment, ":") {
+		return fmt.Sprintf("image %q has no tag, which resolves to the floating \"latest\" tag", image)
+	}
+
+	tag := lastSegment[strings.LastIndex(lastSegment, ":")+1:]
+	if tag == "latest" {
+		return fmt.Sprintf("image %q is pinned to the floating \"latest\" tag", image)
+	}
+
+	return ""
+}
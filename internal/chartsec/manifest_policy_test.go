@@ -0,0 +1,128 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func baseChartEntries(templates ...tarEntry) []tarEntry {
+	entries := []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+		{name: "mychart/values.yaml", content: "image:\n  repository: example/app\n"},
+	}
+
+	return append(entries, templates...)
+}
+
+func TestReport_K8sManifestPolicy(t *testing.T) {
+	tests := map[string]struct {
+		manifest string
+		policy   string
+	}{
+		"hostNetwork": {
+			manifest: "kind: Pod\nspec:\n  hostNetwork: true\n  containers:\n  - name: app\n    image: example/app:1.0.0\n",
+			policy:   k8sManifestPolicy,
+		},
+		"privileged container": {
+			manifest: "kind: Pod\nspec:\n  containers:\n  - name: app\n    image: example/app:1.0.0\n    securityContext:\n      privileged: true\n",
+			policy:   k8sManifestPolicy,
+		},
+		"hostPath volume": {
+			manifest: "kind: Pod\nspec:\n  volumes:\n  - name: data\n    hostPath:\n      path: /var/lib/data\n",
+			policy:   k8sManifestPolicy,
+		},
+		"latest image tag": {
+			manifest: "kind: Pod\nspec:\n  containers:\n  - name: app\n    image: example/app:latest\n",
+			policy:   k8sManifestPolicy,
+		},
+		"missing image tag": {
+			manifest: "kind: Pod\nspec:\n  containers:\n  - name: app\n    image: example/app\n",
+			policy:   k8sManifestPolicy,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			archive := buildChartArchive(t, baseChartEntries(
+				tarEntry{name: "mychart/templates/pod.yaml", content: test.manifest},
+			))
+
+			report, err := NewChartScanner().Report(bytes.NewReader(archive))
+			require.NoError(t, err)
+
+			var found bool
+			for _, f := range report.Findings {
+				if f.Policy == test.policy {
+					found = true
+				}
+			}
+			assert.True(t, found, "expected a %q finding, got %+v", test.policy, report.Findings)
+		})
+	}
+}
+
+func TestReport_K8sManifestPolicy_PinnedImage(t *testing.T) {
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/pod.yaml", content: "kind: Pod\nspec:\n  containers:\n  - name: app\n    image: example/app:1.0.0\n"},
+	))
+
+	report, err := NewChartScanner().Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings)
+}
+
+func TestScan_TemplateDangerousFunc(t *testing.T) {
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/configmap.yaml", content: "data:\n  home: {{ env \"HOME\" }}\n"},
+	))
+
+	err := NewChartScanner().Scan(bytes.NewReader(archive))
+	require.Error(t, err)
+
+	scanErr, ok := err.(*ScanError)
+	require.True(t, ok, "expected a *ScanError, got %T", err)
+
+	var found bool
+	for _, v := range scanErr.Violations {
+		if v.Policy == templateDangerousFuncPolicy {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected a %q violation, got %+v", templateDangerousFuncPolicy, scanErr.Violations)
+}
+
+func TestReport_TemplateSyntaxError(t *testing.T) {
+	archive := buildChartArchive(t, baseChartEntries(
+		tarEntry{name: "mychart/templates/broken.yaml", content: "data:\n  value: {{ .Values.foo\n"},
+	))
+
+	report, err := NewChartScanner().Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	var found bool
+	for _, f := range report.Findings {
+		if f.Policy == templateSyntaxPolicy {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected a %q finding, got %+v", templateSyntaxPolicy, report.Findings)
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import "time"
+
+// Option configures a ChartScanner.
+type Option func(*ChartScanner)
+
+// WithMaxCompressedSize overrides the maximum size, in bytes, a chart
+// archive is allowed to be before decompression.
+func WithMaxCompressedSize(size int64) Option {
+	return func(s *ChartScanner) {
+		s.maxCompressedSize = size
+	}
+}
+
+// WithMaxUncompressedSize overrides the maximum size, in bytes, a chart
+// archive is allowed to expand to once decompressed.
+func WithMaxUncompressedSize(size int64) Option {
+	return func(s *ChartScanner) {
+		s.maxUncompressedSize = size
+	}
+}
+
+// WithMaxFileCount overrides the maximum number of entries a chart archive
+// is allowed to contain.
+func WithMaxFileCount(n int) Option {
+	return func(s *ChartScanner) {
+		s.maxFileCount = n
+	}
+}
+
+// WithMaxCompressionRatio overrides the maximum ratio of uncompressed to
+// compressed bytes a chart archive is allowed to reach at any point during
+// decompression, guarding against zip-bomb style archives.
+func WithMaxCompressionRatio(ratio int64) Option {
+	return func(s *ChartScanner) {
+		s.maxCompressionRatio = ratio
+	}
+}
+
+// WithPolicy registers a Policy, adding it to the scanner's policy registry
+// or replacing the built-in policy of the same name. This lets downstream
+// users add custom rules (image allowlists, required labels, etc.) without
+// forking the scanner.
+func WithPolicy(policy Policy) Option {
+	return func(s *ChartScanner) {
+		s.registerPolicy(policy)
+	}
+}
+
+// WithChartPolicy registers a ChartPolicy, adding it to the scanner's
+// chart-level policy registry or replacing the built-in policy of the same
+// name.
+func WithChartPolicy(policy ChartPolicy) Option {
+	return func(s *ChartScanner) {
+		s.registerChartPolicy(policy)
+	}
+}
+
+// WithDisabledPolicy disables the named policy, which may be one of the
+// scanner's built-in policies or one registered via WithPolicy.
+func WithDisabledPolicy(name string) Option {
+	return func(s *ChartScanner) {
+		s.disabledPolicies[name] = true
+	}
+}
+
+// WithTimeout bounds every scan by d, in addition to whatever context the
+// caller passes to ScanContext/ReportContext (Scan and Report always run
+// with a background context, so without this option they have no deadline
+// of their own). The scan returns early once d elapses, with an error
+// wrapping context.DeadlineExceeded and no report.
+func WithTimeout(d time.Duration) Option {
+	return func(s *ChartScanner) {
+		s.timeout = d
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// defaultChartPolicies returns the built-in chart-level policies a
+// ChartScanner runs unless they are disabled via WithDisabledPolicy or
+// replaced via WithChartPolicy.
+func defaultChartPolicies() []ChartPolicy {
+	return []ChartPolicy{
+		&chartYAMLValidationPolicy{},
+		&templateSyntaxValidationPolicy{},
+		&templateDangerousFuncUsagePolicy{},
+		&k8sManifestLintPolicy{},
+	}
+}
+
+// defaultPolicies returns the built-in policies a ChartScanner runs unless
+// they are disabled via WithDisabledPolicy or replaced via WithPolicy.
+func defaultPolicies() []Policy {
+	return []Policy{
+		&pathTraversalPolicy{},
+		&markdownSanitizationPolicy{},
+	}
+}
+
+const unsafePathPolicy = "unsafe-path"
+
+// driveLetterPathPattern matches Windows drive-letter paths such as
+// "C:/foo" or "C:\foo", which filepath.Clean does not recognize as
+// absolute on non-Windows platforms.
+var driveLetterPathPattern = regexp.MustCompile(`^[a-zA-Z]:[/\\]`)
+
+// pathTraversalPolicy rejects tar entries that could escape the chart
+// directory once extracted: absolute paths, Windows drive-letter paths,
+// "../" traversal, and symlink/hardlink entries whose target resolves
+// outside the chart root. This mirrors the tar-slip protections Helm added
+// via filepath-securejoin.
+type pathTraversalPolicy struct{}
+
+func (p *pathTraversalPolicy) Name() string { return unsafePathPolicy }
+
+func (p *pathTraversalPolicy) Severity() Severity { return SeverityHigh }
+
+func (p *pathTraversalPolicy) Inspect(_ context.Context, file ChartFile) []Violation {
+	header := file.Header
+
+	if v := p.checkPath(header.Name); v != nil {
+		return []Violation{*v}
+	}
+
+	switch header.Typeflag {
+	case tar.TypeSymlink, tar.TypeLink:
+		// A symlink's target is relative to the directory holding the link;
+		// a hardlink's target names another entry, relative to the archive root.
+		target := header.Linkname
+		if header.Typeflag == tar.TypeSymlink && !filepath.IsAbs(filepath.FromSlash(target)) && !driveLetterPathPattern.MatchString(target) {
+			target = filepath.Join(filepath.Dir(header.Name), target)
+		}
+
+		if v := p.checkPath(target); v != nil {
+			return []Violation{*v}
+		}
+	}
+
+	return nil
+}
+
+// checkPath reports a violation if name is absolute, is a Windows
+// drive-letter path, or resolves outside the virtual archive root once ".."
+// components are taken into account. It understands both "/" and "\"
+// separators, since tar entries created on Windows may use either.
+func (p *pathTraversalPolicy) checkPath(name string) *Violation {
+	slashName := strings.ReplaceAll(name, `\`, "/")
+
+	if filepath.IsAbs(slashName) || driveLetterPathPattern.MatchString(name) {
+		return &Violation{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     name,
+			Message:  fmt.Sprintf("chart archive contains an absolute path: %q", name),
+		}
+	}
+
+	if _, err := secureJoin("/", slashName); err != nil {
+		return &Violation{
+			Policy:   p.Name(),
+			Severity: p.Severity(),
+			File:     name,
+			Message:  fmt.Sprintf("chart archive entry escapes the chart root: %q", name),
+		}
+	}
+
+	return nil
+}
+
+// secureJoin resolves name against root, walking name component by
+// component like filepath-securejoin does, and guarantees the result can
+// never climb above root: unlike filepath-securejoin, which clamps such a
+// path to root, it returns an error as soon as a ".." component would.
+// Plain filepath.Clean/filepath.Join are not sufficient here, since they
+// happily produce a path above root (e.g. filepath.Join("/root", "../../x")
+// cleans to "/x", not an error).
+func secureJoin(root, name string) (string, error) {
+	depth := 0
+
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			depth--
+			if depth < 0 {
+				return "", fmt.Errorf("path escapes archive root")
+			}
+		default:
+			depth++
+		}
+	}
+
+	return filepath.Join(root, filepath.FromSlash(name)), nil
+}
+
+const maliciousContentPolicy = "malicious-content"
+
+// markdownSanitizationPolicy flags Markdown files whose content is altered
+// by an HTML/UGC sanitizer, which indicates the presence of unsafe markup
+// (e.g. script tags or event handlers) that could execute when the
+// Markdown is rendered.
+type markdownSanitizationPolicy struct{}
+
+func (p *markdownSanitizationPolicy) Name() string { return maliciousContentPolicy }
+
+func (p *markdownSanitizationPolicy) Severity() Severity { return SeverityHigh }
+
+func (p *markdownSanitizationPolicy) Inspect(ctx context.Context, file ChartFile) []Violation {
+	if !strings.EqualFold(filepath.Ext(file.Name()), ".md") {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	contentStr := string(file.Content)
+	sanitizedContentStr := html.UnescapeString(bluemonday.UGCPolicy().Sanitize(contentStr))
+
+	if contentStr == sanitizedContentStr {
+		return nil
+	}
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.PatchMake(contentStr, sanitizedContentStr)
+
+	return []Violation{{
+		Policy:   p.Name(),
+		Severity: p.Severity(),
+		File:     file.Name(),
+		Message:  "chart contains malicious content in file: " + file.Name(),
+		Context:  dmp.PatchToText(diffs),
+	}}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Severity indicates how serious a policy violation is.
+type Severity int
+
+// Severity levels a Policy can report. Higher values are more severe.
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// ChartFile is a single file extracted from a chart archive, handed to a
+// Policy for inspection.
+type ChartFile struct {
+	// Header is the tar header the file was extracted with, so policies can
+	// inspect metadata such as the name, link target or file mode without
+	// re-reading the archive.
+	Header *tar.Header
+
+	// Content is the file's contents. It is empty for entries that carry no
+	// data, such as directories, symlinks and hardlinks.
+	Content []byte
+}
+
+// Name returns the file's path within the chart archive.
+func (f ChartFile) Name() string {
+	return f.Header.Name
+}
+
+// Violation describes a single policy violation found while scanning a
+// chart.
+type Violation struct {
+	// Policy is the name of the Policy that reported the violation.
+	Policy string
+
+	// Severity is the violation's severity, as reported by the policy.
+	Severity Severity
+
+	// File is the chart-relative path the violation was found in, if any.
+	File string
+
+	// Line is the 1-based line number the violation was found on within
+	// File, or 0 if the policy does not track line numbers.
+	Line int
+
+	// Message is a human-readable description of the violation.
+	Message string
+
+	// Context holds optional additional diagnostic information, such as a
+	// diff of sanitized content.
+	Context string
+}
+
+// Policy inspects chart files and reports any violations it finds. Policies
+// are stateless and safe for concurrent use.
+type Policy interface {
+	// Name uniquely identifies the policy, e.g. "unsafe-path". It is used to
+	// enable/disable and to override policies via ChartScanner options.
+	Name() string
+
+	// Severity is the severity violations reported by this policy are
+	// tagged with.
+	Severity() Severity
+
+	// Inspect examines a single chart file and returns any violations it
+	// finds. It is called once per file in the archive. Implementations
+	// should return promptly when ctx is done.
+	Inspect(ctx context.Context, file ChartFile) []Violation
+}
+
+// Chart is the set of files extracted from a chart archive, handed to a
+// ChartPolicy that needs cross-file context a single ChartFile can't
+// provide, such as rendering a template against values.yaml.
+type Chart struct {
+	Files []ChartFile
+}
+
+// File returns the chart file at path, and whether it was found.
+func (c Chart) File(path string) (ChartFile, bool) {
+	for _, f := range c.Files {
+		if f.Name() == path {
+			return f, true
+		}
+	}
+
+	return ChartFile{}, false
+}
+
+// ChartPolicy inspects the full set of files extracted from a chart
+// together, as opposed to Policy, which inspects files in isolation.
+type ChartPolicy interface {
+	// Name uniquely identifies the policy. It shares its namespace with
+	// Policy names, so enabling/disabling either kind of policy goes
+	// through the same ChartScanner options.
+	Name() string
+
+	// Severity is the severity violations reported by this policy are
+	// tagged with.
+	Severity() Severity
+
+	// InspectChart examines the full chart and returns any violations it
+	// finds. Implementations should return promptly when ctx is done.
+	InspectChart(ctx context.Context, chart Chart) []Violation
+}
+
+// ScanError is returned by Scan when a policy reported a high-severity
+// violation. It can hold several violations, but Scan fills it with only
+// the first high-severity one; Report returns every finding.
+type ScanError struct {
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ScanError) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].Message
+	}
+
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+
+	return fmt.Sprintf("%d policy violations found: %s", len(e.Violations), strings.Join(messages, "; "))
+}
+
+// HighestSeverity returns the highest severity among the aggregated
+// violations.
+func (e *ScanError) HighestSeverity() Severity {
+	highest := SeverityLow
+
+	for _, v := range e.Violations {
+		if v.Severity > highest {
+			highest = v.Severity
+		}
+	}
+
+	return highest
+}
@@ -0,0 +1,160 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding is a single policy violation as reported in a ScanReport: a
+// Violation plus the remediation advice for the policy that found it.
+type Finding struct {
+	Policy      string
+	Severity    Severity
+	File        string
+	Line        int
+	Message     string
+	Context     string
+	Remediation string
+}
+
+// remediationByPolicy gives generic, policy-level remediation advice for
+// every built-in policy. It has no entry for custom policies registered via
+// WithPolicy/WithChartPolicy; Finding.Remediation is simply empty for those.
+var remediationByPolicy = map[string]string{
+	unsafePathPolicy:              "Remove the path traversal, absolute path or symlink/hardlink that escapes the chart root.",
+	maliciousContentPolicy:        "Remove the unsafe HTML/script markup from the Markdown file.",
+	chartYAMLPolicy:               "Fix Chart.yaml so it satisfies the Helm chart schema.",
+	templateSyntaxPolicy:          "Fix the template syntax error.",
+	templateDangerousFuncPolicy:   "Avoid calling functions that read the environment, filesystem or DNS from chart templates.",
+	k8sManifestPolicy:             "Review the rendered manifest and remove the unsafe Kubernetes setting.",
+	compressedArchiveSizePolicy:   "Reduce the chart archive's compressed size.",
+	uncompressedArchiveSizePolicy: "Reduce the chart's uncompressed size.",
+	compressionRatioPolicy:        "Investigate why the archive compresses at an unusually high ratio; it may be a zip bomb.",
+	fileCountPolicy:               "Reduce the number of files packaged in the chart.",
+}
+
+func violationToFinding(v Violation) Finding {
+	return Finding{
+		Policy:      v.Policy,
+		Severity:    v.Severity,
+		File:        v.File,
+		Line:        v.Line,
+		Message:     v.Message,
+		Context:     v.Context,
+		Remediation: remediationByPolicy[v.Policy],
+	}
+}
+
+// ScanReport is the structured result of scanning a chart archive: every
+// finding discovered, plus metadata about the chart and the scan itself.
+type ScanReport struct {
+	ChartName    string
+	ChartVersion string
+
+	Findings []Finding
+
+	StartedAt time.Time
+	Duration  time.Duration
+
+	CompressedSize   int64
+	UncompressedSize int64
+}
+
+// HighestSeverity returns the highest severity among the report's findings,
+// or SeverityLow if it has none.
+func (r *ScanReport) HighestSeverity() Severity {
+	highest := SeverityLow
+
+	for _, f := range r.Findings {
+		if f.Severity > highest {
+			highest = f.Severity
+		}
+	}
+
+	return highest
+}
+
+// scanReportJSON is the JSON wire format for a ScanReport. It exists so
+// Severity renders as its name rather than its underlying int, and chart
+// metadata nests under a single "chart" key.
+type scanReportJSON struct {
+	Chart struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"chart"`
+	Findings []findingJSON `json:"findings"`
+	Scan     struct {
+		StartedAt        time.Time `json:"startedAt"`
+		DurationMillis   int64     `json:"durationMillis"`
+		CompressedSize   int64     `json:"compressedSize"`
+		UncompressedSize int64     `json:"uncompressedSize"`
+	} `json:"scan"`
+}
+
+type findingJSON struct {
+	Policy      string `json:"policy"`
+	Severity    string `json:"severity"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Message     string `json:"message"`
+	Context     string `json:"context,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *ScanReport) MarshalJSON() ([]byte, error) {
+	var doc scanReportJSON
+
+	doc.Chart.Name = r.ChartName
+	doc.Chart.Version = r.ChartVersion
+
+	for _, f := range r.Findings {
+		doc.Findings = append(doc.Findings, findingJSON{
+			Policy:      f.Policy,
+			Severity:    f.Severity.String(),
+			File:        f.File,
+			Line:        f.Line,
+			Message:     f.Message,
+			Context:     f.Context,
+			Remediation: f.Remediation,
+		})
+	}
+
+	doc.Scan.StartedAt = r.StartedAt
+	doc.Scan.DurationMillis = r.Duration.Milliseconds()
+	doc.Scan.CompressedSize = r.CompressedSize
+	doc.Scan.UncompressedSize = r.UncompressedSize
+
+	return json.Marshal(doc)
+}
+
+// chartNameVersion extracts the chart name and version from Chart.yaml, or
+// returns empty strings if the chart has none (or it fails to parse; that
+// is already surfaced as a chartYAMLPolicy finding).
+func chartNameVersion(chart Chart) (name, version string) {
+	file, ok := findFile(chart, "Chart.yaml")
+	if !ok {
+		return "", ""
+	}
+
+	var meta chartMetadata
+	_ = yaml.Unmarshal(file.Content, &meta)
+
+	return meta.Name, meta.Version
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanReport_MarshalJSON(t *testing.T) {
+	report := &ScanReport{
+		ChartName:    "mychart",
+		ChartVersion: "0.1.0",
+		Findings: []Finding{
+			{Policy: unsafePathPolicy, Severity: SeverityHigh, File: "/etc/passwd", Message: "unsafe path", Remediation: remediationByPolicy[unsafePathPolicy]},
+		},
+	}
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	chart, ok := decoded["chart"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "mychart", chart["name"])
+	assert.Equal(t, "0.1.0", chart["version"])
+
+	findings, ok := decoded["findings"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, findings, 1)
+
+	finding, ok := findings[0].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "high", finding["severity"])
+	assert.Equal(t, unsafePathPolicy, finding["policy"])
+}
+
+func TestScanReport_SARIF(t *testing.T) {
+	report := &ScanReport{
+		Findings: []Finding{
+			{Policy: unsafePathPolicy, Severity: SeverityHigh, File: "mychart/link", Line: 3, Message: "unsafe path"},
+		},
+	}
+
+	data, err := report.SARIF()
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, "2.1.0", decoded["version"])
+
+	runs, ok := decoded["runs"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, runs, 1)
+
+	run := runs[0].(map[string]interface{})
+	results := run["results"].([]interface{})
+	require.Len(t, results, 1)
+
+	result := results[0].(map[string]interface{})
+	assert.Equal(t, unsafePathPolicy, result["ruleId"])
+	assert.Equal(t, "error", result["level"])
+}
+
+func TestScanReport_HighestSeverity(t *testing.T) {
+	report := &ScanReport{
+		Findings: []Finding{
+			{Policy: chartYAMLPolicy, Severity: SeverityMedium},
+			{Policy: unsafePathPolicy, Severity: SeverityHigh},
+		},
+	}
+
+	assert.Equal(t, SeverityHigh, report.HighestSeverity())
+}
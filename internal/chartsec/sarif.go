@@ -0,0 +1,156 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import "encoding/json"
+
+// sarifSchemaURI and sarifVersion identify the SARIF 2.1.0 schema, so
+// reports produced by SARIF can be consumed by code-scanning UIs such as
+// GitHub Advanced Security and GitLab.
+const (
+	sarifSchemaURI = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+	sarifVersion   = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	ShortDescription sarifText              `json:"shortDescription"`
+	Help             sarifText              `json:"help"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a chartsec Severity to the SARIF result.level values
+// ("error", "warning" or "note").
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SARIF encodes the report as a SARIF 2.1.0 log, suitable for upload to
+// code-scanning tools that consume that format (GitHub Advanced Security,
+// GitLab, and similar CI integrations).
+func (r *ScanReport) SARIF() ([]byte, error) {
+	rules := make(map[string]sarifRule)
+	var ruleOrder []string // records first-seen order, since rules is a map and Go map iteration is randomized
+
+	var results []sarifResult
+
+	for _, f := range r.Findings {
+		if _, ok := rules[f.Policy]; !ok {
+			ruleOrder = append(ruleOrder, f.Policy)
+
+			rule := sarifRule{
+				ID:               f.Policy,
+				ShortDescription: sarifText{Text: f.Policy},
+			}
+			if f.Remediation != "" {
+				rule.Help = sarifText{Text: f.Remediation}
+			}
+			rules[f.Policy] = rule
+		}
+
+		result := sarifResult{
+			RuleID:  f.Policy,
+			Level:   sarifLevel(f.Severity),
+			Message: sarifText{Text: f.Message},
+		}
+
+		if f.File != "" {
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.File},
+				},
+			}
+			if f.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+
+		results = append(results, result)
+	}
+
+	driver := sarifDriver{Name: "chartsec"}
+	for _, name := range ruleOrder {
+		driver.Rules = append(driver.Rules, rules[name])
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	}
+
+	return json.Marshal(log)
+}
@@ -16,111 +16,265 @@ package chartsec
 
 import (
 	"archive/tar"
-	"bytes"
 	"compress/gzip"
-	"html"
+	"context"
 	"io"
 	"io/ioutil"
-	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/microcosm-cc/bluemonday"
 	"github.com/pkg/errors"
-	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
 const (
 	maxCompressedArchiveSize   = 10 * 1024 * 1024 // 10 MB
 	maxUncompressedArchiveSize = 10 * 1024 * 1024 // 10 MB
+	maxFileCount               = 10000
 )
 
 const (
 	compressedArchiveSizePolicy   = "compressed-archive-size"
 	uncompressedArchiveSizePolicy = "uncompressed-archive-size"
-	maliciousContentPolicy        = "maliciousContent"
+	fileCountPolicy               = "file-count"
 )
 
 // ChartScanner scans a Helm chart archive for security issues.
-type ChartScanner struct{}
+type ChartScanner struct {
+	maxCompressedSize   int64
+	maxUncompressedSize int64
+	maxCompressionRatio int64
+	maxFileCount        int
+	timeout             time.Duration
 
-// NewChartScanner returns a new ChartScanner instance.
-func NewChartScanner() *ChartScanner {
-	return &ChartScanner{}
+	policies         map[string]Policy
+	policyOrder      []string // registration order of policies, for deterministic report output
+	chartPolicies    map[string]ChartPolicy
+	chartPolicyOrder []string // registration order of chartPolicies, for deterministic report output
+
+	disabledPolicies map[string]bool
+}
+
+// registerPolicy adds policy to s.policies, recording its name in
+// s.policyOrder the first time it is registered so Report's findings come
+// out in a stable order regardless of Go's randomized map iteration.
+func (s *ChartScanner) registerPolicy(policy Policy) {
+	name := policy.Name()
+
+	if _, exists := s.policies[name]; !exists {
+		s.policyOrder = append(s.policyOrder, name)
+	}
+
+	s.policies[name] = policy
+}
+
+// registerChartPolicy is registerPolicy for ChartPolicy.
+func (s *ChartScanner) registerChartPolicy(policy ChartPolicy) {
+	name := policy.Name()
+
+	if _, exists := s.chartPolicies[name]; !exists {
+		s.chartPolicyOrder = append(s.chartPolicyOrder, name)
+	}
+
+	s.chartPolicies[name] = policy
+}
+
+// NewChartScanner returns a new ChartScanner instance, configured with the
+// built-in policies and limits unless overridden by opts.
+func NewChartScanner(opts ...Option) *ChartScanner {
+	s := &ChartScanner{
+		maxCompressedSize:   maxCompressedArchiveSize,
+		maxUncompressedSize: maxUncompressedArchiveSize,
+		maxCompressionRatio: defaultMaxCompressionRatio,
+		maxFileCount:        maxFileCount,
+		policies:            make(map[string]Policy),
+		chartPolicies:       make(map[string]ChartPolicy),
+		disabledPolicies:    make(map[string]bool),
+	}
+
+	for _, policy := range defaultPolicies() {
+		s.registerPolicy(policy)
+	}
+
+	for _, policy := range defaultChartPolicies() {
+		s.registerChartPolicy(policy)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Report runs the security scans on a Helm chart archive and returns a
+// ScanReport describing every finding, regardless of severity, along with
+// chart and timing metadata. Unlike Scan, Report returns a non-nil error
+// only when the archive itself could not be processed (a genuinely corrupt
+// archive, for instance) or the scan was canceled or timed out, rather than
+// when policy violations were found.
+func (s *ChartScanner) Report(r io.Reader) (*ScanReport, error) {
+	return s.ReportContext(context.Background(), r)
 }
 
-// Scan runs the security scans on a Helm chart archive.
+// ReportContext is Report, but lets the caller bound or cancel the scan
+// with ctx. If the scanner was configured with WithTimeout, ctx is also
+// bounded by that timeout, whichever expires first.
+func (s *ChartScanner) ReportContext(ctx context.Context, r io.Reader) (*ScanReport, error) {
+	ctx, cancel := s.boundContext(ctx)
+	defer cancel()
+
+	return s.report(ctx, r)
+}
+
+// Scan runs the security scans on a Helm chart archive. It is a thin
+// wrapper around Report kept for backward compatibility: it returns the
+// first high-severity finding as a *ScanError, or nil if the chart raised
+// no high-severity finding. Callers that need the full set of findings,
+// including lower-severity ones, should use Report instead.
 func (s *ChartScanner) Scan(r io.Reader) error {
-	gzbuf := new(bytes.Buffer)
+	return s.ScanContext(context.Background(), r)
+}
 
-	// Make sure the archive does not exceed the maximum size
-	readBytes, err := io.CopyN(gzbuf, r, maxCompressedArchiveSize)
-	if err != nil && err != io.EOF {
-		return errors.Wrap(err, "failed to read chart archive")
+// ScanContext is Scan, but lets the caller bound or cancel the scan with
+// ctx. It returns an error wrapping ctx.Err() if the scan is canceled or
+// times out before completing.
+func (s *ChartScanner) ScanContext(ctx context.Context, r io.Reader) error {
+	report, err := s.ReportContext(ctx, r)
+	if err != nil {
+		return err
 	}
 
-	if err != io.EOF && readBytes == maxCompressedArchiveSize {
-		return &policyViolationError{
-			violation: "chart is too large",
-			policy:    compressedArchiveSizePolicy,
+	for _, f := range report.Findings {
+		if f.Severity == SeverityHigh {
+			return &ScanError{Violations: []Violation{{
+				Policy:   f.Policy,
+				Severity: f.Severity,
+				File:     f.File,
+				Line:     f.Line,
+				Message:  f.Message,
+				Context:  f.Context,
+			}}}
 		}
 	}
 
-	gzr, err := gzip.NewReader(gzbuf)
-	if err != nil {
-		return errors.Wrap(err, "failed to open chart gzip archive")
+	return nil
+}
+
+// boundContext derives a context for a single scan from ctx, also bounding
+// it by the scanner's configured timeout (if any).
+func (s *ChartScanner) boundContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
 
-	tarbuf := new(bytes.Buffer)
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+func (s *ChartScanner) report(ctx context.Context, r io.Reader) (*ScanReport, error) {
+	startedAt := time.Now()
 
-	// Make sure the uncompressed archive does not exceed the maximum size
-	readBytes, err = io.CopyN(tarbuf, gzr, maxUncompressedArchiveSize)
-	if err != nil && err != io.EOF {
-		return errors.Wrap(err, "failed to decompress chart archive")
+	compressedSrc := &countingReader{r: r, max: s.maxCompressedSize, err: errArchiveTooLarge}
+
+	gzr, err := gzip.NewReader(compressedSrc)
+	if v := archiveLimitViolation(err); v != nil {
+		return s.finishReport(startedAt, compressedSrc, nil, []Violation{*v}), nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to open chart gzip archive")
 	}
+	defer gzr.Close() //nolint:errcheck
 
-	if err != io.EOF && readBytes == maxUncompressedArchiveSize {
-		return &policyViolationError{
-			violation: "chart is too large",
-			policy:    uncompressedArchiveSizePolicy,
-		}
+	guarded := &ratioGuardedReader{
+		gzr:             gzr,
+		compressed:      compressedSrc,
+		maxUncompressed: s.maxUncompressedSize,
+		maxRatio:        s.maxCompressionRatio,
 	}
 
-	_ = gzr.Close()
+	tr := tar.NewReader(guarded)
 
-	tr := tar.NewReader(tarbuf)
+	var violations []Violation
+	var files []ChartFile
+
+	for fileCount := 0; ; fileCount++ {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "chart scan canceled")
+		}
 
-	for {
 		header, err := tr.Next()
 		if err == io.EOF {
 			break
+		} else if v := archiveLimitViolation(err); v != nil {
+			return s.finishReport(startedAt, compressedSrc, guarded, append(violations, *v)), nil
 		} else if err != nil {
-			return errors.Wrap(err, "failed to extract chart archive")
+			return nil, errors.Wrap(err, "failed to extract chart archive")
 		}
 
-		fileName := header.Name
+		if fileCount >= s.maxFileCount {
+			return s.finishReport(startedAt, compressedSrc, guarded, append(violations, Violation{
+				Policy:   fileCountPolicy,
+				Severity: SeverityHigh,
+				Message:  "chart contains too many files",
+			})), nil
+		}
 
-		if strings.EqualFold(filepath.Ext(fileName), ".md") {
-			content, err := ioutil.ReadAll(tr)
-			if err != nil {
-				return errors.Wrapf(err, "failed to extract file %q from chart archive", fileName)
+		var content []byte
+		if header.Typeflag == tar.TypeReg {
+			content, err = ioutil.ReadAll(tr)
+			if v := archiveLimitViolation(err); v != nil {
+				return s.finishReport(startedAt, compressedSrc, guarded, append(violations, *v)), nil
+			} else if err != nil {
+				return nil, errors.Wrapf(err, "failed to extract file %q from chart archive", header.Name)
 			}
+		}
 
-			contentStr := string(content)
-			sanitizedContentStr := html.UnescapeString(bluemonday.UGCPolicy().Sanitize(string(content)))
-
-			if contentStr != sanitizedContentStr {
-				dmp := diffmatchpatch.New()
-				diffs := dmp.PatchMake(contentStr, sanitizedContentStr)
-				patch := dmp.PatchToText(diffs)
+		file := ChartFile{Header: header, Content: content}
+		files = append(files, file)
 
-				return &policyViolationError{
-					violation: "chart contains malicious content in file: " + fileName,
-					policy:    maliciousContentPolicy,
-					context:   patch,
-				}
+		for _, name := range s.policyOrder {
+			if s.disabledPolicies[name] {
+				continue
 			}
+
+			violations = append(violations, s.policies[name].Inspect(ctx, file)...)
 		}
 	}
 
-	return nil
+	chart := Chart{Files: files}
+
+	for _, name := range s.chartPolicyOrder {
+		if s.disabledPolicies[name] {
+			continue
+		}
+
+		violations = append(violations, s.chartPolicies[name].InspectChart(ctx, chart)...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "chart scan canceled")
+	}
+
+	report := s.finishReport(startedAt, compressedSrc, guarded, violations)
+	report.ChartName, report.ChartVersion = chartNameVersion(chart)
+
+	return report, nil
+}
+
+// finishReport builds the ScanReport common to every return path of
+// report(), converting the Violations accumulated so far (whether the scan
+// ran to completion or aborted early on an archive limit) into Findings.
+func (s *ChartScanner) finishReport(startedAt time.Time, compressed *countingReader, uncompressed *ratioGuardedReader, violations []Violation) *ScanReport {
+	report := &ScanReport{
+		StartedAt:      startedAt,
+		Duration:       time.Since(startedAt),
+		CompressedSize: compressed.n,
+	}
+
+	if uncompressed != nil {
+		report.UncompressedSize = uncompressed.uncompressed
+	}
+
+	for _, v := range violations {
+		report.Findings = append(report.Findings, violationToFinding(v))
+	}
+
+	return report
 }
@@ -0,0 +1,224 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarEntry describes a single entry to embed in a test chart archive.
+type tarEntry struct {
+	name     string
+	linkname string
+	typeflag byte
+	content  string
+}
+
+// buildChartArchive gzip-compresses a tar archive containing entries,
+// mimicking a (possibly malicious) Helm chart tarball.
+func buildChartArchive(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+
+		header := &tar.Header{
+			Name:     entry.name,
+			Linkname: entry.linkname,
+			Typeflag: typeflag,
+			Size:     int64(len(entry.content)),
+			Mode:     0o644,
+		}
+
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func TestScan_UnsafePaths(t *testing.T) {
+	tests := map[string]struct {
+		entries []tarEntry
+	}{
+		"parent directory traversal": {
+			entries: []tarEntry{{name: "mychart/../../etc/passwd", content: "evil"}},
+		},
+		"absolute path": {
+			entries: []tarEntry{{name: "/etc/passwd", content: "evil"}},
+		},
+		"windows drive letter path": {
+			entries: []tarEntry{{name: `C:/Windows/System32/evil.dll`, content: "evil"}},
+		},
+		"backslash traversal": {
+			entries: []tarEntry{{name: `mychart\..\..\evil`, content: "evil"}},
+		},
+		"symlink escaping chart root": {
+			entries: []tarEntry{{name: "mychart/link", linkname: "../../etc/passwd", typeflag: tar.TypeSymlink}},
+		},
+		"hardlink escaping chart root": {
+			entries: []tarEntry{{name: "mychart/link", linkname: "/etc/passwd", typeflag: tar.TypeLink}},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			archive := buildChartArchive(t, test.entries)
+
+			err := NewChartScanner().Scan(bytes.NewReader(archive))
+			require.Error(t, err)
+
+			scanErr, ok := err.(*ScanError)
+			require.True(t, ok, "expected a *ScanError, got %T", err)
+			require.Len(t, scanErr.Violations, 1)
+			assert.Equal(t, unsafePathPolicy, scanErr.Violations[0].Policy)
+		})
+	}
+}
+
+func TestScan_SafePaths(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "name: mychart\nversion: 0.1.0\n"},
+		{name: "mychart/templates/deployment.yaml", content: "kind: Deployment\n"},
+		{name: "mychart/README.md", content: "# mychart\n"},
+		{name: "mychart/link", linkname: "README.md", typeflag: tar.TypeSymlink},
+	})
+
+	err := NewChartScanner().Scan(bytes.NewReader(archive))
+	assert.NoError(t, err)
+}
+
+func TestReport_AccumulatesAllFindings(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+		{name: "/etc/passwd", content: "evil"},
+		{name: "mychart/README.md", content: `<script>alert(1)</script>`},
+	})
+
+	report, err := NewChartScanner().Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 2)
+
+	var policies []string
+	for _, f := range report.Findings {
+		policies = append(policies, f.Policy)
+	}
+	assert.ElementsMatch(t, []string{unsafePathPolicy, maliciousContentPolicy}, policies)
+}
+
+func TestScan_ReturnsOnlyFirstHighSeverityFinding(t *testing.T) {
+	// Both the unsafe path and the malicious content policy are
+	// SeverityHigh, so Scan's thin ScanError wrapper surfaces only one of
+	// them; Report (above) is how callers get the full set.
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "/etc/passwd", content: "evil"},
+		{name: "mychart/README.md", content: `<script>alert(1)</script>`},
+	})
+
+	err := NewChartScanner().Scan(bytes.NewReader(archive))
+	require.Error(t, err)
+
+	scanErr, ok := err.(*ScanError)
+	require.True(t, ok, "expected a *ScanError, got %T", err)
+	require.Len(t, scanErr.Violations, 1)
+	assert.Contains(t, []string{unsafePathPolicy, maliciousContentPolicy}, scanErr.Violations[0].Policy)
+}
+
+func TestScan_WithDisabledPolicy(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/README.md", content: `<script>alert(1)</script>`},
+	})
+
+	err := NewChartScanner(WithDisabledPolicy(maliciousContentPolicy)).Scan(bytes.NewReader(archive))
+	assert.NoError(t, err)
+}
+
+// blockByExtensionPolicy is a test-only Policy used to exercise WithPolicy.
+type blockByExtensionPolicy struct {
+	ext string
+}
+
+func (p *blockByExtensionPolicy) Name() string       { return "block-by-extension" }
+func (p *blockByExtensionPolicy) Severity() Severity { return SeverityMedium }
+
+func (p *blockByExtensionPolicy) Inspect(_ context.Context, file ChartFile) []Violation {
+	if filepath.Ext(file.Name()) != p.ext {
+		return nil
+	}
+
+	return []Violation{{Policy: p.Name(), Severity: p.Severity(), File: file.Name(), Message: "blocked extension"}}
+}
+
+func TestReport_WithCustomPolicy(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+		{name: "mychart/evil.sh", content: "rm -rf /"},
+	})
+
+	report, err := NewChartScanner(WithPolicy(&blockByExtensionPolicy{ext: ".sh"})).Report(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "block-by-extension", report.Findings[0].Policy)
+}
+
+func TestScanContext_CanceledBeforeScan(t *testing.T) {
+	archive := buildChartArchive(t, []tarEntry{
+		{name: "mychart/Chart.yaml", content: "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewChartScanner().ScanContext(ctx, bytes.NewReader(archive))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReportContext_WithTimeout(t *testing.T) {
+	// Build an archive with enough entries that the scanner is certain to
+	// still be looping over tar entries once the timeout below elapses.
+	var entries []tarEntry
+	for i := 0; i < 1000; i++ {
+		entries = append(entries, tarEntry{name: filepath.Join("mychart", "templates", string(rune('a'+i%26))+".yaml"), content: "kind: ConfigMap\n"})
+	}
+
+	archive := buildChartArchive(t, entries)
+
+	_, err := NewChartScanner(WithTimeout(1)).ReportContext(context.Background(), bytes.NewReader(archive))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,216 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsec
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// dangerousTemplateFuncs are Sprig/text-template functions that reach
+// outside the chart's own data (the environment, the filesystem, DNS) when
+// called. A chart that calls them from a template is trying to observe or
+// affect the host the chart is rendered on, rather than just templating
+// Kubernetes manifests.
+var dangerousTemplateFuncs = map[string]bool{
+	"env":           true,
+	"expandenv":     true,
+	"readFile":      true,
+	"getHostByName": true,
+}
+
+// inTemplatesDir reports whether name has a "templates" path segment that
+// is not its last component, i.e. it is a file stored under a templates/
+// directory rather than a directory entry named "templates" itself.
+func inTemplatesDir(name string) bool {
+	parts := strings.Split(name, "/")
+
+	for i, part := range parts {
+		if part == "templates" && i < len(parts)-1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isChartTemplate reports whether path is a file Helm would render as part
+// of `templates/`, excluding partials (whose name starts with "_") which
+// are only ever included by other templates, never rendered on their own.
+func isChartTemplate(path string) bool {
+	if !inTemplatesDir(path) {
+		return false
+	}
+
+	base := filepath.Base(path)
+
+	return !strings.HasPrefix(base, "_")
+}
+
+// parseChartTemplates parses every template under templates/ (including
+// partials, so that {{ template }}/{{ include }} calls to them resolve) as
+// one associated template set, the way Helm does when rendering a chart.
+func parseChartTemplates(chart Chart) (*template.Template, []Violation) {
+	funcs := sprig.TxtFuncMap()
+
+	// Sprig has no readFile; give each dangerous function missing from it a
+	// no-op stand-in, so a template calling one still parses and is reported
+	// by templateDangerousFuncUsagePolicy rather than as a syntax error.
+	for name := range dangerousTemplateFuncs {
+		if _, ok := funcs[name]; !ok {
+			funcs[name] = func(...interface{}) string { return "" }
+		}
+	}
+
+	root := template.New("chart").Funcs(funcs)
+
+	var violations []Violation
+
+	for _, file := range chart.Files {
+		name := file.Name()
+		if !inTemplatesDir(name) {
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		if ext != ".yaml" && ext != ".yml" && ext != ".tpl" && ext != ".txt" {
+			continue
+		}
+
+		tmpl := root.New(name)
+		if _, err := tmpl.Parse(string(file.Content)); err != nil {
+			violations = append(violations, Violation{
+				Policy:   templateSyntaxPolicy,
+				Severity: SeverityMedium,
+				File:     name,
+				Message:  fmt.Sprintf("template %q failed to parse: %s", name, err),
+			})
+		}
+	}
+
+	return root, violations
+}
+
+const templateSyntaxPolicy = "template-syntax"
+
+// templateSyntaxValidationPolicy parses every chart template with the same
+// function map Helm renders with (Sprig), surfacing syntax errors that
+// would otherwise only show up at install time.
+type templateSyntaxValidationPolicy struct{}
+
+func (p *templateSyntaxValidationPolicy) Name() string { return templateSyntaxPolicy }
+
+func (p *templateSyntaxValidationPolicy) Severity() Severity { return SeverityMedium }
+
+func (p *templateSyntaxValidationPolicy) InspectChart(_ context.Context, chart Chart) []Violation {
+	_, violations := parseChartTemplates(chart)
+
+	return violations
+}
+
+const templateDangerousFuncPolicy = "template-dangerous-func"
+
+// templateDangerousFuncUsagePolicy flags chart templates that call functions
+// which read the environment, the filesystem or DNS, rather than just the
+// values the chart was rendered with.
+type templateDangerousFuncUsagePolicy struct{}
+
+func (p *templateDangerousFuncUsagePolicy) Name() string { return templateDangerousFuncPolicy }
+
+func (p *templateDangerousFuncUsagePolicy) Severity() Severity { return SeverityHigh }
+
+func (p *templateDangerousFuncUsagePolicy) InspectChart(_ context.Context, chart Chart) []Violation {
+	root, _ := parseChartTemplates(chart)
+
+	var violations []Violation
+
+	for _, tmpl := range root.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+
+		for _, name := range findDangerousFuncCalls(tmpl.Tree.Root) {
+			violations = append(violations, Violation{
+				Policy:   p.Name(),
+				Severity: p.Severity(),
+				File:     tmpl.Name(),
+				Message:  fmt.Sprintf("template %q calls the %q function, which reaches outside chart data", tmpl.Name(), name),
+			})
+		}
+	}
+
+	return violations
+}
+
+// findDangerousFuncCalls walks a parsed template's node tree, returning the
+// name of every call to a function in dangerousTemplateFuncs.
+func findDangerousFuncCalls(node parse.Node) []string {
+	var found []string
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+
+		for _, child := range n.Nodes {
+			found = append(found, findDangerousFuncCalls(child)...)
+		}
+	case *parse.ActionNode:
+		found = append(found, findDangerousFuncCalls(n.Pipe)...)
+	case *parse.IfNode:
+		found = append(found, findDangerousFuncCalls(n.Pipe)...)
+		found = append(found, findDangerousFuncCalls(n.List)...)
+		found = append(found, findDangerousFuncCalls(n.ElseList)...)
+	case *parse.RangeNode:
+		found = append(found, findDangerousFuncCalls(n.Pipe)...)
+		found = append(found, findDangerousFuncCalls(n.List)...)
+		found = append(found, findDangerousFuncCalls(n.ElseList)...)
+	case *parse.WithNode:
+		found = append(found, findDangerousFuncCalls(n.Pipe)...)
+		found = append(found, findDangerousFuncCalls(n.List)...)
+		found = append(found, findDangerousFuncCalls(n.ElseList)...)
+	case *parse.TemplateNode:
+		found = append(found, findDangerousFuncCalls(n.Pipe)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+
+		for _, cmd := range n.Cmds {
+			found = append(found, findDangerousFuncCalls(cmd)...)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			ident, ok := arg.(*parse.IdentifierNode)
+			if ok && dangerousTemplateFuncs[ident.Ident] {
+				found = append(found, ident.Ident)
+			}
+
+			// A parenthesized argument, e.g. (env "HOME"), is a nested pipeline.
+			found = append(found, findDangerousFuncCalls(arg)...)
+		}
+	case *parse.ChainNode:
+		found = append(found, findDangerousFuncCalls(n.Node)...)
+	}
+
+	return found
+}